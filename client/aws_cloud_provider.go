@@ -0,0 +1,213 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// awsCloudProvider is the CloudProvider implementation backed by the AWS
+// Auto Scaling and EC2 APIs.
+type awsCloudProvider struct {
+	region string
+	asg    *autoscaling.AutoScaling
+	ec2    *ec2.EC2
+}
+
+func newAWSCloudProvider(region string) *awsCloudProvider {
+	sess := session.Must(session.NewSession(&aws.Config{Region: aws.String(region)}))
+	return &awsCloudProvider{
+		region: region,
+		asg:    autoscaling.New(sess),
+		ec2:    ec2.New(sess),
+	}
+}
+
+// Region returns the configured region, or attempts to discover it from
+// the EC2 instance metadata service if none was configured.
+func (p *awsCloudProvider) Region() (string, error) {
+	if p.region != "" {
+		return p.region, nil
+	}
+
+	sess, err := session.NewSession()
+	if err != nil {
+		return "", err
+	}
+	return ec2metadata.New(sess).Region()
+}
+
+func (p *awsCloudProvider) desiredCapacity(group string) (int64, error) {
+	out, err := p.asg.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(group)},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(out.AutoScalingGroups) == 0 {
+		return 0, fmt.Errorf("client: autoscaling group %v not found", group)
+	}
+	return aws.Int64Value(out.AutoScalingGroups[0].DesiredCapacity), nil
+}
+
+// ScaleOut increments the desired capacity of group by delta.
+func (p *awsCloudProvider) ScaleOut(ctx context.Context, group string, delta int) error {
+	current, err := p.desiredCapacity(group)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.asg.SetDesiredCapacityWithContext(ctx, &autoscaling.SetDesiredCapacityInput{
+		AutoScalingGroupName: aws.String(group),
+		DesiredCapacity:      aws.Int64(current + int64(delta)),
+	})
+	return err
+}
+
+// ScaleIn terminates the instance at nodeIP within group, decrementing the
+// group's desired capacity.
+func (p *awsCloudProvider) ScaleIn(ctx context.Context, group, nodeIP string) error {
+	instanceID, err := p.instanceIDForIP(ctx, nodeIP)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.asg.TerminateInstanceInAutoScalingGroupWithContext(ctx, &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+		InstanceId:                     aws.String(instanceID),
+		ShouldDecrementDesiredCapacity: aws.Bool(true),
+	})
+	return err
+}
+
+func (p *awsCloudProvider) instanceIDForIP(ctx context.Context, ip string) (string, error) {
+	out, err := p.ec2.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("private-ip-address"),
+				Values: []*string{aws.String(ip)},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, res := range out.Reservations {
+		for _, inst := range res.Instances {
+			return aws.StringValue(inst.InstanceId), nil
+		}
+	}
+	return "", fmt.Errorf("client: no instance found with IP address %v", ip)
+}
+
+// RecentInstances returns the n most recently launched instances within
+// group, most recently launched last.
+func (p *awsCloudProvider) RecentInstances(group string, n int) ([]Instance, error) {
+	out, err := p.asg.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(group)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.AutoScalingGroups) == 0 || len(out.AutoScalingGroups[0].Instances) == 0 {
+		return nil, fmt.Errorf("client: autoscaling group %v has no instances", group)
+	}
+
+	var instanceIDs []*string
+	for _, inst := range out.AutoScalingGroups[0].Instances {
+		instanceIDs = append(instanceIDs, inst.InstanceId)
+	}
+
+	descOut, err := p.ec2.DescribeInstances(&ec2.DescribeInstancesInput{InstanceIds: instanceIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []*ec2.Instance
+	for _, res := range descOut.Reservations {
+		instances = append(instances, res.Instances...)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].LaunchTime.Before(*instances[j].LaunchTime)
+	})
+
+	if len(instances) > n {
+		instances = instances[len(instances)-n:]
+	}
+
+	result := make([]Instance, 0, len(instances))
+	for _, inst := range instances {
+		if inst.PrivateIpAddress == nil {
+			continue
+		}
+		result = append(result, Instance{
+			ID:        aws.StringValue(inst.InstanceId),
+			IPAddress: aws.StringValue(inst.PrivateIpAddress),
+		})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("client: unable to determine the most recently launched instances in %v", group)
+	}
+
+	return result, nil
+}
+
+// MostRecentScalingActivity returns the outcome of the most recent scaling
+// activity performed against group.
+func (p *awsCloudProvider) MostRecentScalingActivity(group string) (ScalingActivity, error) {
+	out, err := p.asg.DescribeScalingActivities(&autoscaling.DescribeScalingActivitiesInput{
+		AutoScalingGroupName: aws.String(group),
+		MaxRecords:           aws.Int64(1),
+	})
+	if err != nil {
+		return ScalingActivity{}, err
+	}
+	if len(out.Activities) == 0 {
+		return ScalingActivity{}, fmt.Errorf("client: no scaling activities found for %v", group)
+	}
+
+	activity := out.Activities[0]
+	return ScalingActivity{
+		StatusCode:    aws.StringValue(activity.StatusCode),
+		StatusMessage: aws.StringValue(activity.StatusMessage),
+	}, nil
+}
+
+// DecrementDesiredCapacity decrements the desired capacity of group by one.
+func (p *awsCloudProvider) DecrementDesiredCapacity(group string) error {
+	current, err := p.desiredCapacity(group)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.asg.SetDesiredCapacity(&autoscaling.SetDesiredCapacityInput{
+		AutoScalingGroupName: aws.String(group),
+		DesiredCapacity:      aws.Int64(current - 1),
+	})
+	return err
+}
+
+// TerminateInstance terminates the instance identified by id.
+func (p *awsCloudProvider) TerminateInstance(id string) error {
+	_, err := p.ec2.TerminateInstances(&ec2.TerminateInstancesInput{
+		InstanceIds: []*string{aws.String(id)},
+	})
+	return err
+}
+
+// DetachInstance detaches the instance identified by id from group without
+// terminating it, decrementing the group's desired capacity.
+func (p *awsCloudProvider) DetachInstance(group, id string) error {
+	_, err := p.asg.DetachInstances(&autoscaling.DetachInstancesInput{
+		AutoScalingGroupName:           aws.String(group),
+		InstanceIds:                    []*string{aws.String(id)},
+		ShouldDecrementDesiredCapacity: aws.Bool(true),
+	})
+	return err
+}
@@ -0,0 +1,9 @@
+package client
+
+// basicEstimator always adds exactly one node per scale-out, regardless of
+// how much capacity is actually required.
+type basicEstimator struct{}
+
+func (basicEstimator) Estimate(requiredCapacity, perNodeCapacity int) int {
+	return 1
+}
@@ -0,0 +1,20 @@
+package client
+
+// binpackingEstimator adds enough nodes to cover requiredCapacity given
+// perNodeCapacity, rounding up so capacity is never under-provisioned.
+type binpackingEstimator struct{}
+
+func (binpackingEstimator) Estimate(requiredCapacity, perNodeCapacity int) int {
+	if requiredCapacity <= 0 || perNodeCapacity <= 0 {
+		return 1
+	}
+
+	n := requiredCapacity / perNodeCapacity
+	if requiredCapacity%perNodeCapacity != 0 {
+		n++
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
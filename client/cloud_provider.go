@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// Instance identifies a single worker pool instance by both its cloud
+// provider instance ID and its IP address within the cluster network, so
+// callers never have to guess which identifier a given API expects.
+type Instance struct {
+	// ID is the cloud provider's identifier for the instance, used for
+	// lifecycle operations such as detaching or terminating it.
+	ID string
+
+	// IPAddress is the instance's address on the cluster network, used to
+	// reach it for health checks.
+	IPAddress string
+}
+
+// ScalingActivity describes the outcome of the most recent scaling activity
+// performed against a node group.
+type ScalingActivity struct {
+	// StatusCode is the cloud provider's status code for the activity,
+	// e.g. "Successful", "Failed", "Cancelled".
+	StatusCode string
+
+	// StatusMessage is a human-readable description of the outcome.
+	StatusMessage string
+}
+
+// CloudProvider abstracts the cloud-specific mechanics of scaling a worker
+// pool node group, so callers do not need to know whether they are talking
+// to AWS, GCE, Azure or a test fake.
+type CloudProvider interface {
+	// ScaleOut increments the desired capacity of group by delta.
+	ScaleOut(ctx context.Context, group string, delta int) error
+
+	// ScaleIn terminates the instance at nodeIP within group, decrementing
+	// the group's desired capacity.
+	ScaleIn(ctx context.Context, group, nodeIP string) error
+
+	// RecentInstances returns the n most recently launched instances
+	// within group, most recently launched last.
+	RecentInstances(group string, n int) ([]Instance, error)
+
+	// MostRecentScalingActivity returns the outcome of the most recent
+	// scaling activity performed against group.
+	MostRecentScalingActivity(group string) (ScalingActivity, error)
+
+	// DecrementDesiredCapacity decrements the desired capacity of group by
+	// one, without terminating any instance.
+	DecrementDesiredCapacity(group string) error
+
+	// TerminateInstance terminates the instance identified by id.
+	TerminateInstance(id string) error
+
+	// DetachInstance detaches the instance identified by id from group
+	// without terminating it, decrementing the group's desired capacity.
+	DetachInstance(group, id string) error
+
+	// Region returns the cloud region this provider is operating in.
+	Region() (string, error)
+}
+
+// NewCloudProvider constructs the CloudProvider implementation named by
+// provider. An empty provider defaults to "aws".
+func NewCloudProvider(provider, region string) (CloudProvider, error) {
+	switch provider {
+	case "", "aws":
+		return newAWSCloudProvider(region), nil
+	case "mock":
+		return NewMockCloudProvider(), nil
+	default:
+		return nil, fmt.Errorf("client: unsupported cloud provider %q", provider)
+	}
+}
@@ -0,0 +1,25 @@
+package client
+
+import "fmt"
+
+// Estimator decides how many nodes a scale-out operation should add to the
+// worker pool.
+type Estimator interface {
+	// Estimate returns the number of nodes to add, given the capacity
+	// still required and the number of allocation slots a single node
+	// provides. It never returns a value less than 1.
+	Estimate(requiredCapacity, perNodeCapacity int) int
+}
+
+// NewEstimator constructs the Estimator implementation named by name. An
+// empty name defaults to "basic".
+func NewEstimator(name string) (Estimator, error) {
+	switch name {
+	case "", "basic":
+		return basicEstimator{}, nil
+	case "binpacking":
+		return binpackingEstimator{}, nil
+	default:
+		return nil, fmt.Errorf("client: unsupported estimator %q", name)
+	}
+}
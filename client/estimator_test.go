@@ -0,0 +1,53 @@
+package client
+
+import "testing"
+
+func TestNewEstimatorUnknownName(t *testing.T) {
+	if _, err := NewEstimator("does-not-exist"); err == nil {
+		t.Fatal("expected an error for an unsupported estimator name")
+	}
+}
+
+func TestBasicEstimatorAlwaysAddsOne(t *testing.T) {
+	e := basicEstimator{}
+
+	cases := []struct {
+		requiredCapacity, perNodeCapacity int
+	}{
+		{requiredCapacity: 10, perNodeCapacity: 4},
+		{requiredCapacity: 0, perNodeCapacity: 4},
+		{requiredCapacity: -5, perNodeCapacity: 4},
+	}
+
+	for _, c := range cases {
+		if got := e.Estimate(c.requiredCapacity, c.perNodeCapacity); got != 1 {
+			t.Errorf("Estimate(%v, %v) = %v, want 1", c.requiredCapacity, c.perNodeCapacity, got)
+		}
+	}
+}
+
+func TestBinpackingEstimator(t *testing.T) {
+	e := binpackingEstimator{}
+
+	cases := []struct {
+		name                               string
+		requiredCapacity, perNodeCapacity int
+		want                               int
+	}{
+		{name: "exact fit", requiredCapacity: 8, perNodeCapacity: 4, want: 2},
+		{name: "rounds up", requiredCapacity: 9, perNodeCapacity: 4, want: 3},
+		{name: "less than one node", requiredCapacity: 1, perNodeCapacity: 4, want: 1},
+		{name: "zero required capacity", requiredCapacity: 0, perNodeCapacity: 4, want: 1},
+		{name: "negative required capacity", requiredCapacity: -3, perNodeCapacity: 4, want: 1},
+		{name: "zero per-node capacity", requiredCapacity: 10, perNodeCapacity: 0, want: 1},
+		{name: "negative per-node capacity", requiredCapacity: 10, perNodeCapacity: -1, want: 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := e.Estimate(c.requiredCapacity, c.perNodeCapacity); got != c.want {
+				t.Errorf("Estimate(%v, %v) = %v, want %v", c.requiredCapacity, c.perNodeCapacity, got, c.want)
+			}
+		})
+	}
+}
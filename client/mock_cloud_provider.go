@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MockCloudProvider is an in-memory CloudProvider used by tests that need
+// to exercise scaling logic without making real cloud API calls.
+type MockCloudProvider struct {
+	mu sync.Mutex
+
+	// Activities is keyed by node group and is consulted by
+	// MostRecentScalingActivity.
+	Activities map[string]ScalingActivity
+
+	instances       map[string][]Instance
+	terminated      []string
+	detached        []string
+	desiredCapacity map[string]int
+	scaleOutCalls   int
+	scaleInCalls    int
+}
+
+// NewMockCloudProvider returns an empty MockCloudProvider.
+func NewMockCloudProvider() *MockCloudProvider {
+	return &MockCloudProvider{
+		Activities:      make(map[string]ScalingActivity),
+		instances:       make(map[string][]Instance),
+		desiredCapacity: make(map[string]int),
+	}
+}
+
+// SetInstances seeds the instances RecentInstances will return for
+// group, most-recently-launched last.
+func (m *MockCloudProvider) SetInstances(group string, instances []Instance) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.instances[group] = instances
+}
+
+// Terminated returns the IDs passed to TerminateInstance, in call order.
+func (m *MockCloudProvider) Terminated() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.terminated...)
+}
+
+// Detached returns the IDs passed to DetachInstance, in call order.
+func (m *MockCloudProvider) Detached() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.detached...)
+}
+
+func (m *MockCloudProvider) ScaleOut(ctx context.Context, group string, delta int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.desiredCapacity[group] += delta
+	m.scaleOutCalls++
+	return nil
+}
+
+func (m *MockCloudProvider) ScaleIn(ctx context.Context, group, nodeIP string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.desiredCapacity[group]--
+	m.scaleInCalls++
+	return nil
+}
+
+// ScaleOutCalls returns the number of times ScaleOut has been called.
+func (m *MockCloudProvider) ScaleOutCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.scaleOutCalls
+}
+
+// ScaleInCalls returns the number of times ScaleIn has been called.
+func (m *MockCloudProvider) ScaleInCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.scaleInCalls
+}
+
+func (m *MockCloudProvider) RecentInstances(group string, n int) ([]Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	instances := m.instances[group]
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("client: mock cloud provider has no instances for %v", group)
+	}
+	if len(instances) > n {
+		instances = instances[len(instances)-n:]
+	}
+	return append([]Instance(nil), instances...), nil
+}
+
+func (m *MockCloudProvider) MostRecentScalingActivity(group string) (ScalingActivity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	activity, ok := m.Activities[group]
+	if !ok {
+		return ScalingActivity{}, fmt.Errorf("client: mock cloud provider has no activity recorded for %v", group)
+	}
+	return activity, nil
+}
+
+func (m *MockCloudProvider) DecrementDesiredCapacity(group string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.desiredCapacity[group]--
+	return nil
+}
+
+func (m *MockCloudProvider) TerminateInstance(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.terminated = append(m.terminated, id)
+	return nil
+}
+
+func (m *MockCloudProvider) DetachInstance(group, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.detached = append(m.detached, id)
+	return nil
+}
+
+func (m *MockCloudProvider) Region() (string, error) {
+	return "mock-region-1", nil
+}
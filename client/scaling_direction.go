@@ -0,0 +1,19 @@
+package client
+
+import "github.com/elsevier-core-engineering/replicator/replicator/structs"
+
+// ScalingDirection is re-exported from structs so callers outside of
+// structs (e.g. Runner) can refer to client.ScalingDirectionOut without
+// structs and client importing each other.
+type ScalingDirection = structs.ScalingDirection
+
+const (
+	// ScalingDirectionOut indicates capacity needs to be added.
+	ScalingDirectionOut = structs.ScalingDirectionOut
+
+	// ScalingDirectionIn indicates capacity needs to be removed.
+	ScalingDirectionIn = structs.ScalingDirectionIn
+
+	// ScalingDirectionNone indicates no scaling action is required.
+	ScalingDirectionNone = structs.ScalingDirectionNone
+)
@@ -0,0 +1,22 @@
+// Package logging provides the leveled log helpers used throughout
+// replicator. It wraps the standard library logger so call sites can be
+// grepped for Debug/Info/Error without pulling in a heavier dependency.
+package logging
+
+import "log"
+
+// Debug logs a low-level, operationally verbose message.
+func Debug(format string, args ...interface{}) {
+	log.Printf("[DEBUG] "+format, args...)
+}
+
+// Info logs a routine, operator-relevant message.
+func Info(format string, args ...interface{}) {
+	log.Printf("[INFO] "+format, args...)
+}
+
+// Error logs a message describing a failure that was handled but is worth
+// surfacing to an operator.
+func Error(format string, args ...interface{}) {
+	log.Printf("[ERR] "+format, args...)
+}
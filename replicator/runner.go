@@ -1,6 +1,10 @@
 package replicator
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
 	"time"
 
 	metrics "github.com/armon/go-metrics"
@@ -17,30 +21,103 @@ type Runner struct {
 	// config is the Config that created this Runner. It is used internally to
 	// construct other objects and pass data.
 	config *structs.Config
+
+	// cloudProvider abstracts away the cloud-specific mechanics of scaling a
+	// worker pool node group so clusterScaling does not need to know whether
+	// it is talking to AWS, GCE, Azure or a test fake.
+	cloudProvider client.CloudProvider
+
+	// estimator decides how many nodes a scale-out should add, ranging from
+	// the naive "one node at a time" behavior to bin-packing required and
+	// pending allocations against per-node capacity.
+	estimator client.Estimator
+
+	// healthLock guards leader and lastTick below, which are read from the
+	// health HTTP handler and written from the leader-election loop.
+	healthLock sync.RWMutex
+
+	// leader tracks whether this runner currently holds Nomad leadership.
+	leader bool
+
+	// lastTick records the timestamp of the last successfully completed
+	// scaling tick, so external supervisors can distinguish "not leader"
+	// from "hung".
+	lastTick time.Time
 }
 
 // NewRunner sets up the Runner type.
 func NewRunner(config *structs.Config) (*Runner, error) {
+	provider, err := client.NewCloudProvider(config.ClusterScaling.Provider, config.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	estimator, err := client.NewEstimator(config.ClusterScaling.Estimator)
+	if err != nil {
+		return nil, err
+	}
+
 	runner := &Runner{
-		doneChan: make(chan struct{}),
-		config:   config,
+		doneChan:      make(chan struct{}),
+		config:        config,
+		cloudProvider: provider,
+		estimator:     estimator,
 	}
 	return runner, nil
 }
 
-// Start creates a new runner and uses a ticker to block until the doneChan is
-// closed at which point the ticker is stopped.
+// Start blocks on Nomad leadership: while not the leader it waits and
+// re-checks, and once leadership is acquired it spawns the scaling ticker
+// for as long as leadership is held, tearing it down the moment it is lost.
+// LeaderCheck is still called once per tick inside runAsLeader; what this
+// avoids is running clusterScaling and jobScaling at all while not the
+// leader, rather than starting them every tick just to have them no-op.
 func (r *Runner) Start() {
+	for {
+		select {
+		case <-r.doneChan:
+			return
+		default:
+		}
+
+		haveLeadership := r.config.NomadClient.LeaderCheck()
+		r.setLeader(haveLeadership)
+
+		if !haveLeadership {
+			logging.Debug("core/runner: replicator is not running on the known " +
+				"leader, waiting for leadership before scaling actions are taken")
+
+			select {
+			case <-time.After(time.Second * time.Duration(r.config.ScalingInterval)):
+				continue
+			case <-r.doneChan:
+				return
+			}
+		}
+
+		logging.Info("core/runner: acquired leadership, starting scaling ticker")
+		r.runAsLeader()
+	}
+}
+
+// runAsLeader owns the scaling ticker for as long as this runner holds
+// leadership. It returns as soon as leadership is lost or doneChan is
+// closed, handing control back to Start.
+func (r *Runner) runAsLeader() {
 	ticker := time.NewTicker(time.Second * time.Duration(r.config.ScalingInterval))
+	defer ticker.Stop()
 
 	// Initialize the state tracking object for scaling operations.
 	scalingState := &structs.ScalingState{}
 
-	defer ticker.Stop()
-
 	for {
 		select {
 		case <-ticker.C:
+			if !r.config.NomadClient.LeaderCheck() {
+				logging.Info("core/runner: leadership lost, tearing down scaling ticker")
+				r.setLeader(false)
+				return
+			}
 
 			clusterChan := make(chan bool)
 			go r.clusterScaling(clusterChan, scalingState)
@@ -48,6 +125,8 @@ func (r *Runner) Start() {
 
 			r.jobScaling()
 
+			r.setLastTick(time.Now())
+
 		case <-r.doneChan:
 			return
 		}
@@ -59,26 +138,62 @@ func (r *Runner) Stop() {
 	close(r.doneChan)
 }
 
+// IsLeader reports whether this runner currently holds Nomad leadership.
+func (r *Runner) IsLeader() bool {
+	r.healthLock.RLock()
+	defer r.healthLock.RUnlock()
+	return r.leader
+}
+
+func (r *Runner) setLeader(leader bool) {
+	r.healthLock.Lock()
+	r.leader = leader
+	r.healthLock.Unlock()
+}
+
+func (r *Runner) setLastTick(t time.Time) {
+	r.healthLock.Lock()
+	r.lastTick = t
+	r.healthLock.Unlock()
+}
+
+// HealthHandler returns an http.HandlerFunc reporting this runner's
+// leadership status and the timestamp of its last successful scaling tick,
+// so external supervisors can distinguish "not leader" from "hung".
+func (r *Runner) HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r.healthLock.RLock()
+		status := struct {
+			Leader   bool      `json:"leader"`
+			LastTick time.Time `json:"last_tick"`
+		}{
+			Leader:   r.leader,
+			LastTick: r.lastTick,
+		}
+		r.healthLock.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
 // clusterScaling is the main entry point into the cluster scaling functionality
 // and ties numerous functions together to create an asynchronus function which
 // can be called from the runner.
 func (r *Runner) clusterScaling(done chan bool, scalingState *structs.ScalingState) {
 	nomadClient := r.config.NomadClient
 	scalingEnabled := r.config.ClusterScaling.Enabled
+	group := r.config.ClusterScaling.AutoscalingGroup
+	cooldowns := r.config.ClusterScaling.NodeGroupCooldown(group)
 
-	// Determine if we are running on the leader node, halt scaling
-	// evaluation if not.
-	if haveLeadership := nomadClient.LeaderCheck(); !haveLeadership {
-		logging.Debug("core/runner: replicator is not running on the known leader," +
-			"no cluster scaling actions will be taken")
-		done <- true
-		return
-	}
+	// Leadership is guaranteed by the caller: clusterScaling is only invoked
+	// from runAsLeader, which tears down the ticker the moment leadership
+	// is lost.
 
 	// If a region has not been specified, attempt to dynamically determine what
 	// region we are running in.
 	if r.config.Region == "" {
-		if region, err := client.DescribeAWSRegion(); err == nil {
+		if region, err := r.cloudProvider.Region(); err == nil {
 			r.config.Region = region
 		}
 	}
@@ -89,31 +204,6 @@ func (r *Runner) clusterScaling(done chan bool, scalingState *structs.ScalingSta
 	if scale, err := nomadClient.EvaluateClusterCapacity(clusterCapacity, r.config); err != nil || !scale {
 		logging.Debug("core/runner: scaling operation not required or permitted")
 	} else {
-		// If we reached this point we will be performing AWS interaction so we
-		// create an client connection.
-		asgSess := client.NewAWSAsgService(r.config.Region)
-
-		// Calculate the scaling cooldown threshold.
-		if !scalingState.LastScalingEvent.IsZero() {
-			cooldown := scalingState.LastScalingEvent.Add(
-				time.Duration(r.config.ClusterScaling.CoolDown) * time.Second)
-
-			if time.Now().Before(cooldown) {
-				logging.Info("core/runner: cluster scaling cooldown threshold has "+
-					"not been reached: %v, scaling operations will not be permitted",
-					cooldown)
-
-				done <- true
-				return
-			}
-
-			logging.Debug("core/runner: cluster scaling cooldown threshold %v has "+
-				"been reached, scaling operations will be permitted", cooldown)
-		} else {
-			logging.Info("core/runner: no previous scaling operations have " +
-				"occurred, scaling operations will be permitted.")
-		}
-
 		if clusterCapacity.ScalingDirection == client.ScalingDirectionOut {
 			// If cluster scaling has been disabled, report but do not initiate a
 			// scaling operation.
@@ -125,91 +215,154 @@ func (r *Runner) clusterScaling(done chan bool, scalingState *structs.ScalingSta
 				return
 			}
 
-			// Attempt to increment the desired count of the autoscaling group. If
+			// A scale-out should not be attempted again so soon after a prior
+			// add or a prior failure against this node group. This is gated by
+			// ScaleOutCoolDown, not the ScaleDownDelay* fields below: those
+			// govern how long to wait before scaling a group back down, an
+			// entirely different decision from whether to scale it back out.
+			if until, blocked := scalingState.CooldownRemaining(group,
+				structs.ScaleEventAdd, cooldowns.ScaleOutCoolDown); blocked {
+				logging.Info("core/runner: node group %v scale-out cooldown has "+
+					"not been reached: %v, scaling operations will not be permitted",
+					group, until)
+				done <- true
+				return
+			}
+			if until, blocked := scalingState.CooldownRemaining(group,
+				structs.ScaleEventFailure, cooldowns.ScaleOutCoolDown); blocked {
+				logging.Info("core/runner: node group %v scale-out cooldown has "+
+					"not been reached: %v, scaling operations will not be permitted",
+					group, until)
+				done <- true
+				return
+			}
+
+			// Ask the configured estimator how many nodes this scale-out should
+			// add, accounting for both the capacity required by currently
+			// unschedulable allocations and by allocations still pending
+			// placement.
+			required := clusterCapacity.RequiredCapacity + clusterCapacity.PendingAllocations
+			n := r.estimator.Estimate(required, clusterCapacity.NodeCapacity)
+
+			// Attempt to increment the desired count of the node group by n. If
 			// this fails, log an error and stop further processing.
-			if err := client.ScaleOutCluster(r.config.ClusterScaling.AutoscalingGroup, asgSess); err != nil {
+			if err := r.cloudProvider.ScaleOut(context.Background(), group, n); err != nil {
 				logging.Error("core/runner: unable to successfully initiate a "+
-					"scaling operation against autoscaling group %v: %v",
-					r.config.ClusterScaling.AutoscalingGroup, err)
+					"scaling operation against node group %v: %v", group, err)
 				done <- true
 				return
 			}
 
-			// Attempt to add a new node to the worker pool until we reach the
-			// retry threshold.
-			// TODO (e.westfall): Make the node failure retry threshold a config
-			// option. Waiting on this until after the merge to take advantage of
-			// config flag changes.
-			for scalingState.NodeFailureCount <= r.config.ClusterScaling.RetryThreshold {
-				if scalingState.NodeFailureCount > 0 {
-					logging.Info("core/runner: attempting to launch a new worker node, "+
-						"previous node failures: %v", scalingState.NodeFailureCount)
-				}
+			// We've verified the node group operation completed successfully.
+			// Next we'll identify the n most recently launched instances from
+			// the worker pool node group.
+			newNodes, err := r.cloudProvider.RecentInstances(group, n)
+			if err != nil {
+				logging.Error("core/runner: Failed to identify the most recently "+
+					"launched instances: %v", err)
+				scalingState.NodeFailureCount += n
+				done <- true
+				return
+			}
 
-				// We've verified the autoscaling group operation completed successfully.
-				// Next we'll identify the most recently launched EC2 instance from the
-				// worker pool ASG.
-				newestNode, err := client.GetMostRecentInstance(
-					r.config.ClusterScaling.AutoscalingGroup,
-					r.config.Region,
-				)
-				if err != nil {
-					logging.Error("core/runner: Failed to identify the most recently "+
-						"launched instance: %v", err)
-					scalingState.NodeFailureCount++
-					continue
-				}
+			// Attempt to verify each new worker node has completed bootstrapping
+			// and successfully joined the worker pool. Health is checked in
+			// parallel since the nodes were all launched by the same request.
+			var (
+				wg      sync.WaitGroup
+				mu      sync.Mutex
+				healthy []client.Instance
+				failed  []client.Instance
+			)
+			for _, node := range newNodes {
+				wg.Add(1)
+				go func(node client.Instance) {
+					defer wg.Done()
+					if nomadClient.VerifyNodeHealth(node.IPAddress) {
+						mu.Lock()
+						healthy = append(healthy, node)
+						mu.Unlock()
+						return
+					}
+					mu.Lock()
+					failed = append(failed, node)
+					mu.Unlock()
+				}(node)
+			}
+			wg.Wait()
 
-				// Attempt to verify the new worker node has completed bootstrapping and
-				// successfully joined the worker pool.
-				healthy := nomadClient.VerifyNodeHealth(newestNode)
-				if healthy {
-					// Reset node failure count once we have a verified healthy worker.
-					scalingState.NodeFailureCount = 0
+			if len(healthy) > 0 {
+				// Reset node failure count once we have at least one verified
+				// healthy worker, and start the after-add cooldown.
+				scalingState.NodeFailureCount = 0
+				scalingState.RecordScaleEvent(group, structs.ScaleEventAdd)
+			}
+
+			if len(failed) == 0 {
+				done <- true
+				return
+			}
 
-					// Update the last scaling event timestamp.
-					scalingState.LastScalingEvent = time.Now()
+			metrics.IncrCounter([]string{"cluster", "scale_out_failed"}, float32(len(failed)))
+
+			// The node group's desired capacity may have been incremented
+			// without any instance ever actually launching (quota, capacity or
+			// subnet exhaustion). If every new node failed to come up, inspect
+			// the most recent scaling activity before we assume they are real,
+			// failed instances.
+			if len(healthy) == 0 {
+				if activity, err := r.cloudProvider.MostRecentScalingActivity(group); err == nil &&
+					activity.StatusCode != "Successful" {
+					logging.Error("core/runner: the most recent scaling activity "+
+						"did not complete successfully (%v: %v), treating the new "+
+						"nodes as placeholders and decrementing desired capacity",
+						activity.StatusCode, activity.StatusMessage)
+
+					metrics.IncrCounterWithLabels([]string{"cluster", "scale_out_placeholder_failure"}, 1,
+						[]metrics.Label{{Name: "status_code", Value: activity.StatusCode}})
+
+					if err := r.cloudProvider.DecrementDesiredCapacity(group); err != nil {
+						logging.Error("core/runner: unable to decrement the desired "+
+							"capacity of node group %v: %v", group, err)
+					}
 
+					time.Sleep(time.Second * time.Duration(r.config.ClusterScaling.ScaleOutPlaceholderBackoff))
 					done <- true
 					return
 				}
+			}
 
+			// Only the nodes that failed to become healthy are cleaned up
+			// individually; nodes that joined successfully are left in place.
+			for _, node := range failed {
 				scalingState.NodeFailureCount++
 				logging.Error("core/runner: new node %v failed to successfully join "+
 					"the worker pool, incrementing node failure count to %v and "+
-					"terminating instance", newestNode, scalingState.NodeFailureCount)
-
-				metrics.IncrCounter([]string{"cluster", "scale_out_failed"}, 1)
-
-				// Translate the IP address of the most recent instance to the EC2
-				// instance ID.
-				instanceID := client.TranslateIptoID(newestNode, r.config.Region)
-
-				// If we've reached the retry threshold, disable cluster scaling and
-				// halt.
-				if disabled := r.disableClusterScaling(scalingState); disabled {
-					// Detach the last failed instance and decrement the desired count
-					// of the autoscaling group. This will leave the instance around
-					// for debugging purposes but allow us to cleanly resume cluster
+					"terminating instance", node.ID, scalingState.NodeFailureCount)
+
+				// If we've reached the retry threshold, disable cluster scaling
+				// and detach rather than terminate.
+				if disabled := r.disableClusterScaling(scalingState, group); disabled {
+					// Detach the failed instance and decrement the desired count
+					// of the node group. This will leave the instance around for
+					// debugging purposes but allow us to cleanly resume cluster
 					// scaling without intervention.
-					err := client.DetachInstance(
-						r.config.ClusterScaling.AutoscalingGroup, instanceID, asgSess,
-					)
-					if err != nil {
+					if err := r.cloudProvider.DetachInstance(group, node.ID); err != nil {
 						logging.Error("core/runner: an error occurred while attempting "+
-							"to detach the failed instance from the ASG: %v", err)
+							"to detach the failed instance from the node group: %v", err)
 					}
-
-					done <- true
-					return
+					continue
 				}
 
-				// Attempt to clean up the most recent instance.
-				if err := client.TerminateInstance(instanceID, r.config.Region); err != nil {
+				// Attempt to clean up the failed instance.
+				if err := r.cloudProvider.TerminateInstance(node.ID); err != nil {
 					logging.Error("core/runner: an error occurred while attempting "+
-						"to terminate instance %v: %v", instanceID, err)
+						"to terminate instance %v: %v", node.ID, err)
 				}
 			}
+
+			done <- true
+			return
 		}
 
 		if clusterCapacity.ScalingDirection == client.ScalingDirectionIn {
@@ -222,15 +375,45 @@ func (r *Runner) clusterScaling(done chan bool, scalingState *structs.ScalingSta
 					return
 				}
 
+				// A scale-in should not be attempted too soon after a prior add,
+				// a prior delete, or a prior scale-out failure against this node
+				// group.
+				for kind, delay := range map[structs.ScaleEvent]int{
+					structs.ScaleEventAdd:     cooldowns.ScaleDownDelayAfterAdd,
+					structs.ScaleEventDelete:  cooldowns.ScaleDownDelayAfterDelete,
+					structs.ScaleEventFailure: cooldowns.ScaleDownDelayAfterFailure,
+				} {
+					if until, blocked := scalingState.CooldownRemaining(group, kind, delay); blocked {
+						logging.Info("core/runner: node group %v scale-in cooldown has "+
+							"not been reached: %v, scaling operations will not be permitted",
+							group, until)
+						done <- true
+						return
+					}
+				}
+
+				// Nomad enforces the same guard on its own Job.Scale RPC; we
+				// mirror it here so the autoscaler never races an in-flight
+				// rolling update by draining the node it is deploying to.
+				if nomadClient.HasActiveDeployments(nodeID) {
+					logging.Info("core/runner: node %v has one or more active "+
+						"deployments, scale-in will not be performed", nodeID)
+
+					metrics.IncrCounter([]string{"cluster", "scale_in_blocked_deployment"}, 1)
+
+					done <- true
+					return
+				}
+
 				if err := nomadClient.DrainNode(nodeID); err == nil {
-					logging.Info("core/runner: terminating AWS instance %v", nodeIP)
-					err := client.ScaleInCluster(r.config.ClusterScaling.AutoscalingGroup, nodeIP, asgSess)
+					logging.Info("core/runner: terminating instance %v", nodeIP)
+					err := r.cloudProvider.ScaleIn(context.Background(), r.config.ClusterScaling.AutoscalingGroup, nodeIP)
 					if err != nil {
-						logging.Error("core/runner: unable to successfully terminate AWS "+
+						logging.Error("core/runner: unable to successfully terminate "+
 							"instance %v: %v", nodeID, err)
 					} else {
-						// Update the last scaling event timestamp.
-						scalingState.LastScalingEvent = time.Now()
+						// Start the after-delete cooldown for this node group.
+						scalingState.RecordScaleEvent(group, structs.ScaleEventDelete)
 					}
 				}
 			}
@@ -241,12 +424,16 @@ func (r *Runner) clusterScaling(done chan bool, scalingState *structs.ScalingSta
 	return
 }
 
-func (r *Runner) disableClusterScaling(scalingState *structs.ScalingState) (disabled bool) {
-	// If we've reached the retry threshold, disable cluster scaling and
-	// halt.
-	if scalingState.NodeFailureCount == r.config.ClusterScaling.RetryThreshold {
+func (r *Runner) disableClusterScaling(scalingState *structs.ScalingState, group string) (disabled bool) {
+	// If we've reached or exceeded the retry threshold, disable cluster
+	// scaling and record a scale-out failure against this node group,
+	// starting its after-failure cooldown. Cluster scaling is not
+	// re-enabled when that cooldown elapses: Enabled stays false until it
+	// is set again.
+	if scalingState.NodeFailureCount >= r.config.ClusterScaling.RetryThreshold {
 		disabled = true
 		r.config.ClusterScaling.Enabled = false
+		scalingState.RecordScaleEvent(group, structs.ScaleEventFailure)
 
 		logging.Error("core/runner: attempts to add new nodes to the "+
 			"worker pool have failed %v times. Cluster scaling will be "+
@@ -265,11 +452,8 @@ func (r *Runner) jobScaling() {
 	consulClient := r.config.ConsulClient
 	nomadClient := r.config.NomadClient
 
-	// Determine if we are running on the leader node, halt if not.
-	if haveLeadership := nomadClient.LeaderCheck(); !haveLeadership {
-		logging.Debug("core/runner: replicator is not running on the known leader, no job scaling actions will be taken")
-		return
-	}
+	// Leadership is guaranteed by the caller: jobScaling is only invoked from
+	// runAsLeader, which tears down the ticker the moment leadership is lost.
 
 	// Pull the list of all currently running jobs which have a defined scaling
 	// document. Fail quickly if we can't retrieve this list.
@@ -290,9 +474,32 @@ func (r *Runner) jobScaling() {
 		// require scaling.
 		i := 0
 
-		for _, group := range job.GroupScalingPolicies {
+		for idx := range job.GroupScalingPolicies {
+			// Index into the slice rather than ranging by value: the Min/Max
+			// clamp below must mutate the group actually held by
+			// job.GroupScalingPolicies, the slice JobScale submits, not a
+			// throwaway copy produced by the range statement.
+			group := &job.GroupScalingPolicies[idx]
 			if group.Scaling.ScaleDirection == client.ScalingDirectionOut || group.Scaling.ScaleDirection == client.ScalingDirectionIn {
 				if job.Enabled && r.config.JobScaling.Enabled {
+					// Clamp the target count to the group's configured Min/Max
+					// before we ever submit it, closing the same class of bug
+					// fixed on Nomad's server-side Job.Scale endpoint for
+					// operators running against older Nomad releases.
+					if group.Scaling.Count > group.Scaling.Max {
+						logging.Info("core/runner: clamping target count for job "+
+							"\"%v\" group \"%v\" to max %v (requested %v)",
+							job.JobName, group.GroupName, group.Scaling.Max, group.Scaling.Count)
+						group.Scaling.Count = group.Scaling.Max
+						metrics.IncrCounter([]string{"job", "scale_clamped_max"}, 1)
+					} else if group.Scaling.Count < group.Scaling.Min {
+						logging.Info("core/runner: clamping target count for job "+
+							"\"%v\" group \"%v\" to min %v (requested %v)",
+							job.JobName, group.GroupName, group.Scaling.Min, group.Scaling.Count)
+						group.Scaling.Count = group.Scaling.Min
+						metrics.IncrCounter([]string{"job", "scale_clamped_min"}, 1)
+					}
+
 					logging.Debug("core/runner: scaling for job \"%v\" is enabled; a scaling operation (%v) will be requested for group \"%v\"",
 						job.JobName, group.Scaling.ScaleDirection, group.GroupName)
 					i++
@@ -307,6 +514,15 @@ func (r *Runner) jobScaling() {
 		// as to scale you must submit the whole job file currently. The JobScale
 		// function takes care of scaling groups independently.
 		if i > 0 {
+			// Refuse to submit a scaling request while the job's latest
+			// deployment is still in flight; this mirrors the guard Nomad
+			// applies server-side so older Nomad releases are still protected.
+			if nomadClient.HasActiveDeployments(job.JobName) {
+				logging.Debug("core/runner: job \"%v\" has an active deployment, "+
+					"scaling request will not be submitted", job.JobName)
+				continue
+			}
+
 			nomadClient.JobScale(job)
 		}
 	}
@@ -0,0 +1,418 @@
+package replicator
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elsevier-core-engineering/replicator/client"
+	"github.com/elsevier-core-engineering/replicator/replicator/structs"
+)
+
+// fakeNomadClient is a structs.NomadClient test double whose behavior is
+// driven entirely by the fields set by the test. It records the id it was
+// called with for each HasActiveDeployments call site so tests can pin
+// which identifier semantics (node ID vs job name) reached it.
+type fakeNomadClient struct {
+	mu sync.Mutex
+
+	leader bool
+
+	// leaderSequence, if set, overrides leader: LeaderCheck returns each
+	// value in turn, then repeats the last value once exhausted, so tests
+	// can exercise leadership being gained and lost over successive calls.
+	leaderSequence []bool
+	leaderCalls    int
+
+	clusterCapacity *structs.ClusterCapacity
+	nodeHealthy     bool
+
+	leastAllocatedNodeID string
+	leastAllocatedNodeIP string
+
+	activeDeployments map[string]bool
+	deploymentChecks  []string
+
+	drainCalled bool
+	drainedNode string
+
+	jobScaleCalled bool
+	lastScaledJob  *structs.JobScalingPolicy
+}
+
+func (f *fakeNomadClient) LeaderCheck() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.leaderSequence) == 0 {
+		return f.leader
+	}
+	if f.leaderCalls >= len(f.leaderSequence) {
+		return f.leaderSequence[len(f.leaderSequence)-1]
+	}
+	leader := f.leaderSequence[f.leaderCalls]
+	f.leaderCalls++
+	return leader
+}
+
+func (f *fakeNomadClient) EvaluateClusterCapacity(capacity *structs.ClusterCapacity, config *structs.Config) (bool, error) {
+	if f.clusterCapacity == nil {
+		return false, nil
+	}
+	*capacity = *f.clusterCapacity
+	return true, nil
+}
+
+func (f *fakeNomadClient) VerifyNodeHealth(nodeIP string) bool { return f.nodeHealthy }
+
+func (f *fakeNomadClient) LeastAllocatedNode(capacity *structs.ClusterCapacity) (string, string) {
+	return f.leastAllocatedNodeID, f.leastAllocatedNodeIP
+}
+
+func (f *fakeNomadClient) HasActiveDeployments(id string) bool {
+	f.deploymentChecks = append(f.deploymentChecks, id)
+	return f.activeDeployments[id]
+}
+
+func (f *fakeNomadClient) DrainNode(nodeID string) error {
+	f.drainCalled = true
+	f.drainedNode = nodeID
+	return nil
+}
+
+func (f *fakeNomadClient) EvaluateJobScaling(jobs []*structs.JobScalingPolicy) {}
+
+func (f *fakeNomadClient) JobScale(job *structs.JobScalingPolicy) {
+	f.jobScaleCalled = true
+	f.lastScaledJob = job
+}
+
+// fakeConsulClient is a structs.ConsulClient test double that returns a
+// fixed set of job scaling policies.
+type fakeConsulClient struct {
+	policies []*structs.JobScalingPolicy
+}
+
+func (f *fakeConsulClient) GetJobScalingPolicies(config *structs.Config, nomadClient structs.NomadClient) ([]*structs.JobScalingPolicy, error) {
+	return f.policies, nil
+}
+
+// TestClusterScaleInBlockedByActiveDeployment verifies that clusterScaling
+// checks HasActiveDeployments using the node's Nomad node ID, and skips
+// draining and terminating the node when a deployment is active.
+func TestClusterScaleInBlockedByActiveDeployment(t *testing.T) {
+	nomadClient := &fakeNomadClient{
+		leader: true,
+		clusterCapacity: &structs.ClusterCapacity{
+			ScalingDirection: structs.ScalingDirectionIn,
+		},
+		leastAllocatedNodeID: "node-1",
+		leastAllocatedNodeIP: "10.0.0.1",
+		activeDeployments:    map[string]bool{"node-1": true},
+	}
+
+	runner := &Runner{
+		config: &structs.Config{
+			Region:      "us-east-1",
+			NomadClient: nomadClient,
+			ClusterScaling: &structs.ClusterScaling{
+				Enabled: true,
+			},
+		},
+	}
+
+	done := make(chan bool, 1)
+	runner.clusterScaling(done, &structs.ScalingState{})
+
+	if len(nomadClient.deploymentChecks) != 1 || nomadClient.deploymentChecks[0] != "node-1" {
+		t.Fatalf("expected HasActiveDeployments to be called with node ID %q, got %v", "node-1", nomadClient.deploymentChecks)
+	}
+	if nomadClient.drainCalled {
+		t.Fatal("expected DrainNode not to be called while a deployment is active")
+	}
+}
+
+// TestJobScalingBlockedByActiveDeployment verifies that jobScaling checks
+// HasActiveDeployments using the Nomad job name, and skips submitting the
+// scaling request when a deployment is active.
+func TestJobScalingBlockedByActiveDeployment(t *testing.T) {
+	job := &structs.JobScalingPolicy{
+		JobName: "example",
+		Enabled: true,
+		GroupScalingPolicies: []structs.GroupScalingPolicy{
+			{
+				GroupName: "cache",
+				Scaling:   structs.ScalingPolicy{ScaleDirection: structs.ScalingDirectionOut},
+			},
+		},
+	}
+
+	nomadClient := &fakeNomadClient{
+		leader:            true,
+		activeDeployments: map[string]bool{"example": true},
+	}
+	consulClient := &fakeConsulClient{policies: []*structs.JobScalingPolicy{job}}
+
+	runner := &Runner{
+		config: &structs.Config{
+			NomadClient:  nomadClient,
+			ConsulClient: consulClient,
+			JobScaling:   &structs.JobScaling{Enabled: true},
+		},
+	}
+
+	runner.jobScaling()
+
+	if len(nomadClient.deploymentChecks) != 1 || nomadClient.deploymentChecks[0] != "example" {
+		t.Fatalf("expected HasActiveDeployments to be called with job name %q, got %v", "example", nomadClient.deploymentChecks)
+	}
+	if nomadClient.jobScaleCalled {
+		t.Fatal("expected JobScale not to be called while a deployment is active")
+	}
+}
+
+// TestJobScalingClampsCountBeforeSubmission verifies that a target count
+// outside a group's configured Min/Max is clamped on the
+// JobScalingPolicy actually passed to JobScale, not just on a throwaway
+// range-loop copy.
+func TestJobScalingClampsCountBeforeSubmission(t *testing.T) {
+	job := &structs.JobScalingPolicy{
+		JobName: "example",
+		Enabled: true,
+		GroupScalingPolicies: []structs.GroupScalingPolicy{
+			{
+				GroupName: "cache",
+				Scaling: structs.ScalingPolicy{
+					ScaleDirection: structs.ScalingDirectionOut,
+					Min:            1,
+					Max:            5,
+					Count:          10,
+				},
+			},
+		},
+	}
+
+	nomadClient := &fakeNomadClient{leader: true}
+	consulClient := &fakeConsulClient{policies: []*structs.JobScalingPolicy{job}}
+
+	runner := &Runner{
+		config: &structs.Config{
+			NomadClient:  nomadClient,
+			ConsulClient: consulClient,
+			JobScaling:   &structs.JobScaling{Enabled: true},
+		},
+	}
+
+	runner.jobScaling()
+
+	if nomadClient.lastScaledJob == nil {
+		t.Fatal("expected JobScale to be called")
+	}
+
+	got := nomadClient.lastScaledJob.GroupScalingPolicies[0].Scaling.Count
+	if got != 5 {
+		t.Fatalf("expected clamped count of 5 to reach JobScale, got %v", got)
+	}
+}
+
+// TestClusterScalingPlaceholderFailureDoesNotTerminateLiveInstance verifies
+// that when the most recent scaling activity did not complete successfully,
+// clusterScaling decrements the desired capacity instead of terminating the
+// unhealthy instance it found: the failed activity never launched a new
+// instance, so the one found is an existing, live instance.
+func TestClusterScalingPlaceholderFailureDoesNotTerminateLiveInstance(t *testing.T) {
+	const group = "workers"
+
+	cloudProvider := client.NewMockCloudProvider()
+	cloudProvider.SetInstances(group, []client.Instance{{ID: "i-live", IPAddress: "10.0.0.9"}})
+	cloudProvider.Activities[group] = client.ScalingActivity{StatusCode: "Failed", StatusMessage: "insufficient capacity"}
+
+	nomadClient := &fakeNomadClient{
+		leader: true,
+		clusterCapacity: &structs.ClusterCapacity{
+			ScalingDirection: structs.ScalingDirectionOut,
+		},
+		nodeHealthy: false,
+	}
+
+	estimator, err := client.NewEstimator("")
+	if err != nil {
+		t.Fatalf("unexpected error constructing estimator: %v", err)
+	}
+
+	runner := &Runner{
+		cloudProvider: cloudProvider,
+		estimator:     estimator,
+		config: &structs.Config{
+			Region:      "us-east-1",
+			NomadClient: nomadClient,
+			ClusterScaling: &structs.ClusterScaling{
+				Enabled:                    true,
+				AutoscalingGroup:           group,
+				RetryThreshold:             0,
+				ScaleOutPlaceholderBackoff: 0,
+			},
+		},
+	}
+
+	done := make(chan bool, 1)
+	runner.clusterScaling(done, &structs.ScalingState{})
+
+	if len(cloudProvider.Terminated()) != 0 {
+		t.Fatalf("expected the placeholder instance not to be terminated, got %v", cloudProvider.Terminated())
+	}
+}
+
+// TestScaleOutNotBlockedByScaleDownDelay verifies that a scale-out is gated
+// only by ScaleOutCoolDown, and is not blocked by a large
+// ScaleDownDelayAfterAdd even when a prior add was recorded very recently.
+func TestScaleOutNotBlockedByScaleDownDelay(t *testing.T) {
+	const group = "workers"
+
+	cloudProvider := client.NewMockCloudProvider()
+	cloudProvider.SetInstances(group, []client.Instance{{ID: "i-1", IPAddress: "10.0.0.2"}})
+
+	nomadClient := &fakeNomadClient{
+		leader: true,
+		clusterCapacity: &structs.ClusterCapacity{
+			ScalingDirection: structs.ScalingDirectionOut,
+		},
+		nodeHealthy: true,
+	}
+
+	estimator, err := client.NewEstimator("")
+	if err != nil {
+		t.Fatalf("unexpected error constructing estimator: %v", err)
+	}
+
+	runner := &Runner{
+		cloudProvider: cloudProvider,
+		estimator:     estimator,
+		config: &structs.Config{
+			Region:      "us-east-1",
+			NomadClient: nomadClient,
+			ClusterScaling: &structs.ClusterScaling{
+				Enabled:          true,
+				AutoscalingGroup: group,
+				RetryThreshold:   3,
+				NodeGroupCooldowns: map[string]structs.NodeGroupCooldown{
+					group: {
+						ScaleOutCoolDown:       0,
+						ScaleDownDelayAfterAdd: 3600,
+					},
+				},
+			},
+		},
+	}
+
+	scalingState := &structs.ScalingState{}
+	scalingState.RecordScaleEvent(group, structs.ScaleEventAdd)
+
+	done := make(chan bool, 1)
+	runner.clusterScaling(done, scalingState)
+
+	if got := cloudProvider.ScaleOutCalls(); got != 1 {
+		t.Fatalf("expected ScaleOut to be called once despite the large "+
+			"ScaleDownDelayAfterAdd, got %v calls", got)
+	}
+}
+
+// TestScaleInNotBlockedByScaleOutCoolDown verifies that a scale-in is gated
+// only by the ScaleDownDelay* fields, and is not blocked by a large
+// ScaleOutCoolDown even when a prior add was recorded very recently.
+func TestScaleInNotBlockedByScaleOutCoolDown(t *testing.T) {
+	const group = "workers"
+
+	cloudProvider := client.NewMockCloudProvider()
+
+	nomadClient := &fakeNomadClient{
+		leader: true,
+		clusterCapacity: &structs.ClusterCapacity{
+			ScalingDirection: structs.ScalingDirectionIn,
+		},
+		leastAllocatedNodeID: "node-1",
+		leastAllocatedNodeIP: "10.0.0.1",
+	}
+
+	runner := &Runner{
+		cloudProvider: cloudProvider,
+		config: &structs.Config{
+			Region:      "us-east-1",
+			NomadClient: nomadClient,
+			ClusterScaling: &structs.ClusterScaling{
+				Enabled:          true,
+				AutoscalingGroup: group,
+				NodeGroupCooldowns: map[string]structs.NodeGroupCooldown{
+					group: {
+						ScaleOutCoolDown: 3600,
+					},
+				},
+			},
+		},
+	}
+
+	scalingState := &structs.ScalingState{}
+	scalingState.RecordScaleEvent(group, structs.ScaleEventAdd)
+
+	done := make(chan bool, 1)
+	runner.clusterScaling(done, scalingState)
+
+	if got := cloudProvider.ScaleInCalls(); got != 1 {
+		t.Fatalf("expected ScaleIn to be called once despite the large "+
+			"ScaleOutCoolDown, got %v calls", got)
+	}
+	if !nomadClient.drainCalled {
+		t.Fatal("expected DrainNode to be called")
+	}
+}
+
+// TestRunnerLeadershipToggle verifies that Start cleanly starts and stops
+// the scaling ticker as leadership is gained and lost, and that IsLeader
+// reflects each transition.
+func TestRunnerLeadershipToggle(t *testing.T) {
+	nomadClient := &fakeNomadClient{
+		leaderSequence: []bool{false, true, false},
+	}
+	consulClient := &fakeConsulClient{}
+
+	runner := &Runner{
+		doneChan: make(chan struct{}),
+		config: &structs.Config{
+			NomadClient:     nomadClient,
+			ConsulClient:    consulClient,
+			ScalingInterval: 1,
+			ClusterScaling:  &structs.ClusterScaling{},
+			JobScaling:      &structs.JobScaling{},
+		},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		runner.Start()
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !runner.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for runner to observe leadership")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for runner.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for runner to observe lost leadership")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	runner.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Start to return after Stop")
+	}
+}
@@ -0,0 +1,25 @@
+package structs
+
+// ClusterCapacity describes the outcome of a cluster capacity evaluation:
+// whether the worker pool needs to scale, and in which direction.
+type ClusterCapacity struct {
+	// ScalingDirection indicates whether capacity needs to be added,
+	// removed, or left alone.
+	ScalingDirection ScalingDirection
+
+	// RequiredCapacity is the number of additional allocation slots the
+	// cluster needs in order to place its currently unschedulable
+	// allocations.
+	RequiredCapacity int
+
+	// PendingAllocations is the number of allocations surfaced by
+	// EvaluateJobScaling that are queued to be placed but have not yet
+	// been scheduled. These also consume capacity and must be accounted
+	// for alongside RequiredCapacity when estimating how many nodes a
+	// scale-out should add.
+	PendingAllocations int
+
+	// NodeCapacity is the number of allocation slots a single worker pool
+	// node can host.
+	NodeCapacity int
+}
@@ -0,0 +1,98 @@
+package structs
+
+// Config is the runtime configuration used to construct a Runner.
+type Config struct {
+	// Region is the cloud region replicator is operating in. If empty, the
+	// cloud provider is asked to determine it dynamically.
+	Region string
+
+	// ScalingInterval is the number of seconds between scaling evaluations.
+	ScalingInterval int
+
+	// NomadClient is used to evaluate and act on Nomad cluster and job
+	// state.
+	NomadClient NomadClient
+
+	// ConsulClient is used to retrieve job scaling policy documents.
+	ConsulClient ConsulClient
+
+	// ClusterScaling holds the worker pool scaling configuration.
+	ClusterScaling *ClusterScaling
+
+	// JobScaling holds the Nomad job scaling configuration.
+	JobScaling *JobScaling
+}
+
+// ClusterScaling holds the worker pool (cluster) scaling configuration.
+type ClusterScaling struct {
+	// Enabled indicates whether cluster scaling operations are permitted.
+	Enabled bool
+
+	// Provider selects the CloudProvider implementation used to manage the
+	// worker pool node group. An empty value defaults to "aws".
+	Provider string
+
+	// Estimator selects the Estimator implementation used to decide how
+	// many nodes a scale-out should add. An empty value defaults to
+	// "basic".
+	Estimator string
+
+	// AutoscalingGroup is the name of the cloud node group backing the
+	// Nomad worker pool.
+	AutoscalingGroup string
+
+	// RetryThreshold is the number of consecutive new-node health check
+	// failures tolerated before cluster scaling is disabled.
+	RetryThreshold int
+
+	// ScaleOutPlaceholderBackoff is the number of seconds to wait after
+	// decrementing the desired capacity for a scale-out whose instance
+	// never actually launched, before the next attempt is made.
+	ScaleOutPlaceholderBackoff int
+
+	// NodeGroupCooldowns holds the per-node-group scaling cooldown
+	// configuration, keyed by node group name.
+	NodeGroupCooldowns map[string]NodeGroupCooldown
+}
+
+// NodeGroupCooldown holds the scale-out and scale-down flap-prevention
+// delays for a single node group. The scale-down delays are named after,
+// and mirror, the equivalent delays exposed by the Kubernetes
+// cluster-autoscaler; ScaleOutCoolDown is a distinct field because gating
+// a scale-out is a different decision from gating a scale-down and must
+// not be tied to the same delay.
+type NodeGroupCooldown struct {
+	// ScaleOutCoolDown is the minimum time, in seconds, to wait before
+	// attempting another scale-out against this node group after a prior
+	// add or a prior scale-out failure.
+	ScaleOutCoolDown int
+
+	// ScaleDownDelayAfterAdd is the minimum time, in seconds, to wait
+	// before scaling this node group down after a node was added to it.
+	ScaleDownDelayAfterAdd int
+
+	// ScaleDownDelayAfterDelete is the minimum time, in seconds, to wait
+	// before scaling this node group down again after a node was removed
+	// from it.
+	ScaleDownDelayAfterDelete int
+
+	// ScaleDownDelayAfterFailure is the minimum time, in seconds, to wait
+	// before scaling this node group down after a scale-out failure.
+	ScaleDownDelayAfterFailure int
+}
+
+// NodeGroupCooldown returns the configured cooldown for group, or the zero
+// value if none was configured.
+func (c *ClusterScaling) NodeGroupCooldown(group string) NodeGroupCooldown {
+	if c.NodeGroupCooldowns == nil {
+		return NodeGroupCooldown{}
+	}
+
+	return c.NodeGroupCooldowns[group]
+}
+
+// JobScaling holds the Nomad job scaling configuration.
+type JobScaling struct {
+	// Enabled indicates whether job scaling operations are permitted.
+	Enabled bool
+}
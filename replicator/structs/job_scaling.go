@@ -0,0 +1,41 @@
+package structs
+
+// JobScalingPolicy describes a Nomad job that has one or more groups with a
+// scaling policy document defined.
+type JobScalingPolicy struct {
+	// JobName is the Nomad job identifier.
+	JobName string
+
+	// Enabled indicates whether the job's scaling document has scaling
+	// enabled.
+	Enabled bool
+
+	// GroupScalingPolicies holds the scaling policy for each task group
+	// within the job that defines one.
+	GroupScalingPolicies []GroupScalingPolicy
+}
+
+// GroupScalingPolicy describes the scaling policy for a single task group
+// within a job.
+type GroupScalingPolicy struct {
+	// GroupName is the task group name.
+	GroupName string
+
+	// Scaling is the evaluated scaling policy for this group.
+	Scaling ScalingPolicy
+}
+
+// ScalingPolicy is the evaluated scaling decision for a single task group.
+type ScalingPolicy struct {
+	// ScaleDirection indicates whether this group needs to scale out,
+	// scale in, or do nothing.
+	ScaleDirection ScalingDirection
+
+	// Min and Max are the configured bounds on the group's task count.
+	Min int
+	Max int
+
+	// Count is the target task count this scaling decision would submit,
+	// before Min/Max have been enforced.
+	Count int
+}
@@ -0,0 +1,46 @@
+package structs
+
+// NomadClient is the interface implemented by anything that can evaluate
+// Nomad cluster and job state on replicator's behalf.
+type NomadClient interface {
+	// LeaderCheck returns whether this replicator instance is running on
+	// the known Nomad cluster leader.
+	LeaderCheck() bool
+
+	// EvaluateClusterCapacity determines whether the worker pool needs to
+	// scale, populating capacity accordingly. The returned bool indicates
+	// whether a scaling operation is required and permitted.
+	EvaluateClusterCapacity(capacity *ClusterCapacity, config *Config) (bool, error)
+
+	// VerifyNodeHealth reports whether the node at nodeIP has successfully
+	// joined the worker pool and is healthy.
+	VerifyNodeHealth(nodeIP string) bool
+
+	// LeastAllocatedNode identifies the worker node with the least
+	// allocated capacity, returning its Nomad node ID and IP address.
+	LeastAllocatedNode(capacity *ClusterCapacity) (nodeID, nodeIP string)
+
+	// HasActiveDeployments reports whether id - a Nomad node ID when
+	// evaluating cluster scale-in, or a job name when evaluating job
+	// scaling - has one or more deployments currently in flight.
+	HasActiveDeployments(id string) bool
+
+	// DrainNode drains all allocations from the given Nomad node ID.
+	DrainNode(nodeID string) error
+
+	// EvaluateJobScaling determines the scaling requirements of each
+	// group within each of the supplied job scaling policies, updating
+	// them in place.
+	EvaluateJobScaling(jobs []*JobScalingPolicy)
+
+	// JobScale submits the scaling decision captured in job to Nomad.
+	JobScale(job *JobScalingPolicy)
+}
+
+// ConsulClient is the interface implemented by anything that can retrieve
+// job scaling policy documents on replicator's behalf.
+type ConsulClient interface {
+	// GetJobScalingPolicies returns the scaling policy for every
+	// currently running job that has scaling enabled.
+	GetJobScalingPolicies(config *Config, nomadClient NomadClient) ([]*JobScalingPolicy, error)
+}
@@ -0,0 +1,16 @@
+package structs
+
+// ScalingDirection indicates the action, if any, a scaling evaluation has
+// determined is required.
+type ScalingDirection string
+
+const (
+	// ScalingDirectionOut indicates capacity needs to be added.
+	ScalingDirectionOut ScalingDirection = "out"
+
+	// ScalingDirectionIn indicates capacity needs to be removed.
+	ScalingDirectionIn ScalingDirection = "in"
+
+	// ScalingDirectionNone indicates no scaling action is required.
+	ScalingDirectionNone ScalingDirection = "none"
+)
@@ -0,0 +1,73 @@
+package structs
+
+import (
+	"sync"
+	"time"
+)
+
+// ScaleEvent identifies the kind of scaling event recorded against a node
+// group, used to key per-event cooldowns.
+type ScaleEvent string
+
+const (
+	// ScaleEventAdd is recorded when a node was successfully added to a
+	// node group.
+	ScaleEventAdd ScaleEvent = "add"
+
+	// ScaleEventDelete is recorded when a node was removed from a node
+	// group.
+	ScaleEventDelete ScaleEvent = "delete"
+
+	// ScaleEventFailure is recorded when a scale-out attempt against a
+	// node group failed.
+	ScaleEventFailure ScaleEvent = "failure"
+)
+
+// ScalingState tracks the flap-prevention state needed across scaling
+// evaluations: the running count of consecutive node failures, and per
+// node group and event kind, the timestamp of the most recent occurrence.
+type ScalingState struct {
+	mu sync.Mutex
+
+	// NodeFailureCount is the number of consecutive new-node health check
+	// failures observed since the last successful add.
+	NodeFailureCount int
+
+	lastEvent map[string]map[ScaleEvent]time.Time
+}
+
+// RecordScaleEvent timestamps the occurrence of event against group as
+// now, starting that event's cooldown.
+func (s *ScalingState) RecordScaleEvent(group string, event ScaleEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastEvent == nil {
+		s.lastEvent = make(map[string]map[ScaleEvent]time.Time)
+	}
+	if s.lastEvent[group] == nil {
+		s.lastEvent[group] = make(map[ScaleEvent]time.Time)
+	}
+
+	s.lastEvent[group][event] = time.Now()
+}
+
+// CooldownRemaining reports whether fewer than delaySeconds have elapsed
+// since event was last recorded against group. When blocked is true, until
+// is the time at which the cooldown will have elapsed.
+func (s *ScalingState) CooldownRemaining(group string, event ScaleEvent, delaySeconds int) (until time.Time, blocked bool) {
+	if delaySeconds <= 0 {
+		return time.Time{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last, ok := s.lastEvent[group][event]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	until = last.Add(time.Second * time.Duration(delaySeconds))
+	return until, time.Now().Before(until)
+}